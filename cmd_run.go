@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/urfave/cli/v2"
+)
+
+var planIDFlag = &cli.StringFlag{
+	Name:     "plan_id",
+	Usage:    "Run id",
+	Required: true,
+}
+
+var runCommand = &cli.Command{
+	Name:  "run",
+	Usage: "Inspect and control runs",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "get",
+			Usage: "Show the plan JSON output for a run",
+			Flags: []cli.Flag{planIDFlag},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				p, err := client.GetPlan(c.String("plan_id"))
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s", p)
+				return nil
+			},
+		},
+		{
+			Name:  "apply",
+			Usage: "Apply a run",
+			Flags: []cli.Flag{
+				planIDFlag,
+				&cli.StringFlag{Name: "message", Usage: "Apply message"},
+			},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				if err := client.ApplyRun(c.String("plan_id"), c.String("message")); err != nil {
+					return err
+				}
+				fmt.Printf("Run id %s applied\n", c.String("plan_id"))
+				return nil
+			},
+		},
+		{
+			Name:  "discard",
+			Usage: "Discard a run",
+			Flags: []cli.Flag{
+				planIDFlag,
+				&cli.StringFlag{Name: "message", Usage: "Discard message"},
+			},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				if err := client.DiscardRun(c.String("plan_id"), c.String("message")); err != nil {
+					return err
+				}
+				fmt.Printf("Run id %s discarded\n", c.String("plan_id"))
+				return nil
+			},
+		},
+		{
+			Name:  "cancel",
+			Usage: "Cancel a run",
+			Flags: []cli.Flag{
+				planIDFlag,
+				&cli.StringFlag{Name: "message", Usage: "Cancel message"},
+			},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				if err := client.CancelRun(c.String("plan_id"), c.String("message")); err != nil {
+					return err
+				}
+				fmt.Printf("Run id %s cancelled\n", c.String("plan_id"))
+				return nil
+			},
+		},
+		{
+			Name:  "list",
+			Usage: "List runs for a workspace",
+			Flags: []cli.Flag{workspaceNameFlag},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				runs, err := client.ListRuns(c.String("workspace_name"))
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s", runs)
+				return nil
+			},
+		},
+		{
+			Name:  "apply_status",
+			Usage: "Show the apply status for a run",
+			Flags: []cli.Flag{planIDFlag},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				a, err := client.GetApply(c.String("plan_id"))
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s", a)
+				return nil
+			},
+		},
+		{
+			Name:  "plan_logs",
+			Usage: "Show the plan logs for a run",
+			Flags: []cli.Flag{planIDFlag},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				logs, err := client.GetLogs(c.String("plan_id"), PlanOperation)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s", logs)
+				return nil
+			},
+		},
+		{
+			Name:  "apply_logs",
+			Usage: "Show the apply logs for a run",
+			Flags: []cli.Flag{planIDFlag},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				logs, err := client.GetLogs(c.String("plan_id"), ApplyOperation)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s", logs)
+				return nil
+			},
+		},
+		{
+			Name:  "wait",
+			Usage: "Block until a run reaches a terminal state, streaming its logs",
+			Flags: []cli.Flag{
+				planIDFlag,
+				&cli.DurationFlag{Name: "poll_interval", Value: 5 * time.Second, Usage: "Polling interval"},
+				&cli.DurationFlag{Name: "timeout", Usage: "Give up after this long (0 = no timeout)"},
+			},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				return waitAndStream(client, client.Ctx, c.String("plan_id"), WaitOptions{
+					PollInterval: c.Duration("poll_interval"),
+					Timeout:      c.Duration("timeout"),
+				})
+			},
+		},
+		{
+			Name:  "status",
+			Usage: "Show plan, cost estimate, policy check, and run-task results for a run",
+			Flags: []cli.Flag{planIDFlag},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				status, err := client.GetRunStatus(c.String("plan_id"))
+				if err != nil {
+					return err
+				}
+				js, err := json.Marshal(status)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s", js)
+				return nil
+			},
+		},
+		{
+			Name:  "confirm",
+			Usage: "Apply a run, refusing if it exceeds cost or policy gates",
+			Flags: []cli.Flag{
+				planIDFlag,
+				&cli.StringFlag{Name: "message", Usage: "Apply message"},
+				&cli.Float64Flag{Name: "require-cost-under", Usage: "Refuse to apply if the projected monthly cost delta is at or above this amount"},
+				&cli.BoolFlag{Name: "require-policy-pass", Usage: "Refuse to apply if any hard-mandatory policy check failed"},
+			},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				err = client.ConfirmRun(c.String("plan_id"), c.String("message"), ConfirmOptions{
+					RequireCostUnder:  c.Float64("require-cost-under"),
+					RequirePolicyPass: c.Bool("require-policy-pass"),
+				})
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Run id %s applied\n", c.String("plan_id"))
+				return nil
+			},
+		},
+	},
+}
+
+// waitAndStream streams plan logs to stderr while WaitForRun polls the run to
+// completion and writes its JSON status events to stdout, then streams the
+// apply logs if the run was applied, stopping early on SIGINT. Logs and status go to separate streams so the two writers
+// never interleave into each other's output.
+func waitAndStream(client *Connection, ctx context.Context, runID string, opts WaitOptions) error {
+	c, cancel := contextWithInterrupt(ctx)
+	defer cancel()
+
+	planErr := make(chan error, 1)
+	go func() { planErr <- client.StreamLogs(c, runID, PlanOperation, os.Stderr) }()
+
+	r, err := client.WaitForRun(c, runID, opts, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if r.Status == tfe.RunApplying || r.Status == tfe.RunApplied {
+		if err := client.StreamLogs(c, runID, ApplyOperation, os.Stderr); err != nil {
+			return err
+		}
+	}
+	if err := <-planErr; err != nil && err != context.Canceled {
+		return err
+	}
+	return nil
+}
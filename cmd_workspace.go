@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+var workspaceNameFlag = &cli.StringFlag{
+	Name:     "workspace_name",
+	Aliases:  []string{"w"},
+	Usage:    "Workspace name",
+	Required: true,
+}
+
+var workspaceCommand = &cli.Command{
+	Name:  "workspace",
+	Usage: "Manage TFE workspaces",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "list",
+			Usage: "List workspaces in the organization",
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				workspaces, err := client.ListWorkspaces()
+				if err != nil {
+					return err
+				}
+				for _, w := range workspaces {
+					fmt.Println(w)
+				}
+				return nil
+			},
+		},
+		{
+			Name:  "create",
+			Usage: "Create a new workspace",
+			Flags: []cli.Flag{
+				workspaceNameFlag,
+				&cli.StringFlag{
+					Name:  "work_dir",
+					Usage: "Working directory",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				w, err := client.CreateWorkspace(c.String("workspace_name"), c.String("work_dir"))
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%+v\n", w)
+				return nil
+			},
+		},
+		{
+			Name:  "get",
+			Usage: "Show a workspace",
+			Flags: []cli.Flag{workspaceNameFlag},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				ws, err := client.GetWorkspace(c.String("workspace_name"))
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s", ws)
+				return nil
+			},
+		},
+		{
+			Name:  "add_repo",
+			Usage: "Attach a VCS repository to a workspace",
+			Flags: []cli.Flag{
+				workspaceNameFlag,
+				&cli.StringFlag{
+					Name:     "oauth_client_id",
+					Usage:    "OAuth client ID",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "branch",
+					Usage:    "Repository branch",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "repo_url",
+					Usage:    "Repository in format organization/repository",
+					Required: true,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				vcsRepo, err := client.GetVCSProviderFromOAuthClient(c.String("oauth_client_id"), c.String("branch"), c.String("repo_url"))
+				if err != nil {
+					return err
+				}
+				options := &tfe.WorkspaceUpdateOptions{
+					VCSRepo: vcsRepo,
+				}
+				return client.UpdateWorkspace(c.String("workspace_name"), options)
+			},
+		},
+		{
+			Name:  "add_tfe_var",
+			Usage: "Add a Terraform variable to a workspace",
+			Flags: []cli.Flag{
+				workspaceNameFlag,
+				&cli.StringFlag{Name: "var_name", Required: true, Usage: "Variable name"},
+				&cli.StringFlag{Name: "var_value", Required: true, Usage: "Variable value"},
+				&cli.StringFlag{Name: "var_description", Usage: "Variable description"},
+				&cli.BoolFlag{Name: "is_hcl", Usage: "Make variable HCL"},
+				&cli.BoolFlag{Name: "is_sensitive", Usage: "Make variable sensitive"},
+			},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				return client.AddTerraformVariable(
+					c.String("var_name"),
+					c.String("workspace_name"),
+					c.String("var_value"),
+					c.String("var_description"),
+					c.Bool("is_hcl"),
+					c.Bool("is_sensitive"),
+				)
+			},
+		},
+		{
+			Name:  "add_env_var",
+			Usage: "Add an environment variable to a workspace",
+			Flags: []cli.Flag{
+				workspaceNameFlag,
+				&cli.StringFlag{Name: "var_name", Required: true, Usage: "Variable name"},
+				&cli.StringFlag{Name: "var_value", Required: true, Usage: "Variable value"},
+				&cli.StringFlag{Name: "var_description", Usage: "Variable description"},
+				&cli.BoolFlag{Name: "is_sensitive", Usage: "Make variable sensitive"},
+			},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				return client.AddEnvironmentVariable(
+					c.String("var_name"),
+					c.String("workspace_name"),
+					c.String("var_value"),
+					c.String("var_description"),
+					c.Bool("is_sensitive"),
+				)
+			},
+		},
+		{
+			Name:  "plan",
+			Usage: "Queue a plan run on a workspace",
+			Flags: []cli.Flag{
+				workspaceNameFlag,
+				&cli.StringFlag{Name: "message", Usage: "Plan message"},
+				&cli.BoolFlag{Name: "wait", Usage: "Block and stream logs until the run finishes"},
+				&cli.DurationFlag{Name: "poll_interval", Value: 5 * time.Second, Usage: "Polling interval when --wait is set"},
+				&cli.DurationFlag{Name: "timeout", Usage: "Give up after this long when --wait is set (0 = no timeout)"},
+			},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				id, err := client.RunPlan(c.String("workspace_name"), c.String("message"))
+				if err != nil {
+					return err
+				}
+				if !c.Bool("wait") {
+					fmt.Printf("{\"RunID\": \"%s\", \"Status\": \"planning\"}", id)
+					return nil
+				}
+				return waitAndStream(client, client.Ctx, id, WaitOptions{
+					PollInterval: c.Duration("poll_interval"),
+					Timeout:      c.Duration("timeout"),
+				})
+			},
+		},
+		{
+			Name:  "apply-config",
+			Usage: "Reconcile workspaces against a declarative YAML spec",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "file",
+					Aliases:  []string{"f"},
+					Usage:    "Path to the workspaces YAML file",
+					Required: true,
+				},
+				&cli.BoolFlag{Name: "dry-run", Usage: "Report the diff without changing anything"},
+				&cli.BoolFlag{Name: "prune", Usage: "Delete workspaces and variables absent from the file"},
+			},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				cfg, err := loadWorkspacesConfig(c.String("file"))
+				if err != nil {
+					return err
+				}
+				diffs, err := client.ApplyConfig(cfg, ReconcileOptions{
+					DryRun: c.Bool("dry-run"),
+					Prune:  c.Bool("prune"),
+				})
+				if err != nil {
+					return err
+				}
+				js, err := json.Marshal(diffs)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s", js)
+				return nil
+			},
+		},
+		{
+			Name:  "assign_variable_set",
+			Usage: "Assign a variable set to a workspace",
+			Flags: []cli.Flag{
+				workspaceNameFlag,
+				&cli.StringFlag{
+					Name:     "variable_set",
+					Usage:    "Variable set name",
+					Required: true,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				if err := client.AssignVariableSet(c.String("workspace_name"), c.String("variable_set")); err != nil {
+					return err
+				}
+				vs, err := client.ReadVariableSet(c.String("variable_set"))
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s", vs)
+				return nil
+			},
+		},
+	},
+}
+
+// loadWorkspacesConfig reads and parses a `workspace apply-config` spec file.
+func loadWorkspacesConfig(path string) (WorkspacesConfig, error) {
+	var cfg WorkspacesConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	err = yaml.Unmarshal(data, &cfg)
+	return cfg, err
+}
+
+var oauthClientCommand = &cli.Command{
+	Name:  "oauth_client",
+	Usage: "Inspect configured OAuth clients",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "list",
+			Usage: "List OAuth clients",
+			Action: func(c *cli.Context) error {
+				client, err := connectionFromContext(c)
+				if err != nil {
+					return err
+				}
+				clients, err := client.ListOAuthClients()
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s", clients)
+				return nil
+			},
+		},
+	},
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+// LogOperation identifies which phase of a run a log stream belongs to.
+type LogOperation string
+
+const (
+	PlanOperation  LogOperation = "plan"
+	ApplyOperation LogOperation = "apply"
+)
+
+type Connection struct {
+	Client *tfe.Client
+	Org    string
+	// Ctx is the base context used by every Connection method that doesn't
+	// take its own (WaitForRun, StreamLogs), typically carrying the --timeout
+	// deadline set up in main. Defaults to context.Background() if nil.
+	Ctx context.Context
+	// PageSize is the page size requested on every paginated List call. 0
+	// leaves it to the TFE API's own default.
+	PageSize int
+}
+
+func NewConnection(ctx context.Context, config *tfe.Config, org string) (*Connection, error) {
+	client, err := tfe.NewClient(config)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &Connection{
+		Client: client,
+		Org:    org,
+		Ctx:    ctx,
+	}, err
+}
+
+func (c *Connection) ListWorkspaces() ([]string, error) {
+	options := &tfe.WorkspaceListOptions{
+		ListOptions: tfe.ListOptions{PageSize: c.PageSize},
+	}
+	items, err := paginate(func(page int) ([]*tfe.Workspace, int, error) {
+		options.ListOptions.PageNumber = page
+		ws, err := c.Client.Workspaces.List(c.Ctx, c.Org, options)
+		if err != nil {
+			return nil, 0, classifyError("workspaces", err)
+		}
+		return ws.Items, ws.NextPage, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	workspaces := make([]string, len(items))
+	for i, w := range items {
+		workspaces[i] = w.Name
+	}
+	return workspaces, nil
+}
+
+func (c *Connection) CreateWorkspace(name string, workingDir string) (*tfe.Workspace, error) {
+	ctx := c.Ctx
+	w, err := c.Client.Workspaces.Create(ctx, c.Org, tfe.WorkspaceCreateOptions{
+		Name:             tfe.String(name),
+		AutoApply:        tfe.Bool(false),
+		WorkingDirectory: &workingDir,
+	})
+	if err != nil {
+		return nil, classifyError("workspace "+name, err)
+	}
+	return w, nil
+}
+
+func (c *Connection) ReadWorkspace(name string) (*tfe.Workspace, error) {
+	ctx := c.Ctx
+	w, err := c.Client.Workspaces.Read(ctx, c.Org, name)
+	if err != nil {
+		return nil, classifyError("workspace "+name, err)
+	}
+	return w, nil
+}
+
+func (c *Connection) GetWorkspace(name string) ([]byte, error) {
+	ws, err := c.ReadWorkspace(name)
+	if err != nil {
+		return nil, err
+	}
+	var branch, repoIdentifier string
+	if ws.VCSRepo != nil {
+		branch = ws.VCSRepo.Branch
+		repoIdentifier = ws.VCSRepo.Identifier
+	}
+	wsMap := map[string]interface{}{
+		"Name":             ws.Name,
+		"WorkingDirectory": ws.WorkingDirectory,
+		"Branch":           branch,
+		"RepoID":           repoIdentifier,
+		"Locked":           ws.Locked,
+	}
+	return json.Marshal(wsMap)
+}
+
+func (c *Connection) UpdateWorkspace(name string, options *tfe.WorkspaceUpdateOptions) error {
+	ctx := c.Ctx
+	w, err := c.ReadWorkspace(name)
+	if err != nil {
+		return err
+	}
+	_, err = c.Client.Workspaces.Update(ctx, c.Org, w.Name, *options)
+	if err != nil {
+		return classifyError("workspace "+name, err)
+	}
+	return nil
+}
+
+func (c *Connection) ListOAuthClients() ([]byte, error) {
+	options := &tfe.OAuthClientListOptions{
+		ListOptions: tfe.ListOptions{PageSize: c.PageSize},
+	}
+	items, err := paginate(func(page int) ([]*tfe.OAuthClient, int, error) {
+		options.ListOptions.PageNumber = page
+		ts, err := c.Client.OAuthClients.List(c.Ctx, c.Org, options)
+		if err != nil {
+			return nil, 0, classifyError("oauth clients", err)
+		}
+		return ts.Items, ts.NextPage, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	clients := make([]map[string]string, len(items))
+	for i, t := range items {
+		clients[i] = map[string]string{
+			"Name": *t.Name,
+			"Id":   t.ID,
+		}
+	}
+	return json.Marshal(&clients)
+}
+
+func (c *Connection) ReadOAuthClient(name string) (*tfe.OAuthClient, error) {
+	ctx := c.Ctx
+	oc, err := c.Client.OAuthClients.Read(ctx, name)
+	if err != nil {
+		return nil, classifyError("oauth client "+name, err)
+	}
+	return oc, nil
+}
+
+func (c *Connection) GetVCSProviderFromOAuthClient(clientName string, branch string, repoIdentifier string) (*tfe.VCSRepoOptions, error) {
+	oauthclient, err := c.ReadOAuthClient(clientName)
+	if err != nil {
+		return nil, err
+	}
+	vcsrepo := &tfe.VCSRepoOptions{
+		Branch:       &branch,
+		Identifier:   &repoIdentifier,
+		OAuthTokenID: &oauthclient.OAuthTokens[0].ID,
+	}
+	return vcsrepo, nil
+}
+
+func (c *Connection) RunPlan(name string, message string) (string, error) {
+	w, err := c.ReadWorkspace(name)
+	if err != nil {
+		return "", err
+	}
+	ctx := c.Ctx
+	options := tfe.RunCreateOptions{
+		Workspace: w,
+		Message:   &message,
+	}
+	r, err := c.Client.Runs.Create(ctx, options)
+	if err != nil {
+		return "", classifyError("run", err)
+	}
+	return r.ID, nil
+}
+
+func (c *Connection) DiscardRun(runID string, message string) error {
+	ctx := c.Ctx
+	options := tfe.RunDiscardOptions{
+		Comment: &message,
+	}
+	if err := c.Client.Runs.Discard(ctx, runID, options); err != nil {
+		return classifyError("run "+runID, err)
+	}
+	return nil
+}
+
+func (c *Connection) CancelRun(runID string, message string) error {
+	ctx := c.Ctx
+	options := tfe.RunCancelOptions{
+		Comment: &message,
+	}
+	if err := c.Client.Runs.Cancel(ctx, runID, options); err != nil {
+		return classifyError("run "+runID, err)
+	}
+	return nil
+}
+
+func (c *Connection) ListRuns(workspaceName string) ([]byte, error) {
+	w, err := c.ReadWorkspace(workspaceName)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &tfe.RunListOptions{
+		ListOptions: tfe.ListOptions{PageSize: c.PageSize},
+	}
+	items, err := paginate(func(page int) ([]*tfe.Run, int, error) {
+		options.ListOptions.PageNumber = page
+		rs, err := c.Client.Runs.List(c.Ctx, w.ID, options)
+		if err != nil {
+			return nil, 0, classifyError("runs for "+workspaceName, err)
+		}
+		return rs.Items, rs.NextPage, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]map[string]string, len(items))
+	for i, r := range items {
+		runs[i] = map[string]string{
+			"ID":        r.ID,
+			"Status":    string(r.Status),
+			"CreatedAt": fmt.Sprintf("%s", r.CreatedAt),
+		}
+	}
+	return json.Marshal(&runs)
+}
+
+func (c *Connection) GetPlan(runID string) ([]byte, error) {
+	ctx := c.Ctx
+	r, err := c.Client.Runs.Read(ctx, runID)
+	if err != nil {
+		return nil, classifyError("run "+runID, err)
+	}
+	p, err := c.Client.Plans.ReadJSONOutput(ctx, r.Plan.ID)
+	if err != nil {
+		return nil, classifyError("plan for run "+runID, err)
+	}
+	return p, nil
+}
+
+func (c *Connection) ApplyRun(runID string, message string) error {
+	ctx := c.Ctx
+	options := tfe.RunApplyOptions{
+		Comment: &message,
+	}
+	if err := c.Client.Runs.Apply(ctx, runID, options); err != nil {
+		return classifyError("run "+runID, err)
+	}
+	return nil
+}
+
+func (c *Connection) GetApply(runID string) ([]byte, error) {
+	ctx := c.Ctx
+	r, err := c.Client.Runs.Read(ctx, runID)
+	if err != nil {
+		return nil, classifyError("run "+runID, err)
+	}
+	a, err := c.Client.Applies.Read(ctx, r.Apply.ID)
+	if err != nil {
+		return nil, classifyError("apply for run "+runID, err)
+	}
+	return json.Marshal(a)
+}
+
+func (c *Connection) GetLogs(runID string, operation LogOperation) ([]byte, error) {
+	ctx := c.Ctx
+	r, err := c.Client.Runs.Read(ctx, runID)
+	if err != nil {
+		return nil, classifyError("run "+runID, err)
+	}
+	var logs io.Reader
+	if operation == PlanOperation {
+		logs, err = c.Client.Plans.Logs(ctx, r.Plan.ID)
+	}
+	if operation == ApplyOperation {
+		logs, err = c.Client.Applies.Logs(ctx, r.Apply.ID)
+	}
+	if err != nil {
+		return nil, classifyError(string(operation)+" logs for run "+runID, err)
+	}
+	return ParseLogs(logs, runID)
+}
+
+func ParseLogs(logs io.Reader, runID string) ([]byte, error) {
+	var l string
+	buffer := make([]byte, 1000)
+	for {
+		n, err := logs.Read(buffer)
+		l = fmt.Sprintf("%s%s", l, buffer[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	type Logs struct {
+		ID   string
+		Logs string
+	}
+	return json.Marshal(&Logs{ID: runID, Logs: l})
+}
+
+func (c *Connection) addTerraformVariable(name *string, wsName *string, value *string, description *string, hcl *bool, sensitive *bool, category *tfe.CategoryType) (*tfe.Variable, error) {
+	ctx := c.Ctx
+	options := &tfe.VariableCreateOptions{
+		Key:         name,
+		Description: description,
+		HCL:         hcl,
+		Category:    category,
+		Value:       value,
+		Sensitive:   sensitive,
+	}
+	w, err := c.ReadWorkspace(*wsName)
+	if err != nil {
+		return nil, err
+	}
+	v, err := c.Client.Variables.Create(ctx, w.ID, *options)
+	if err != nil {
+		return nil, classifyError("variable "+*name, err)
+	}
+	return v, nil
+}
+
+func (c *Connection) AddTerraformVariable(name string, wsName string, value string, description string, hcl bool, sensitive bool) error {
+	category := tfe.CategoryTerraform
+	_, err := c.addTerraformVariable(&name, &wsName, &value, &description, &hcl, &sensitive, &category)
+	return err
+}
+
+func (c *Connection) AddEnvironmentVariable(name string, wsName string, value string, description string, sensitive bool) error {
+	hcl := false
+	category := tfe.CategoryEnv
+	_, err := c.addTerraformVariable(&name, &wsName, &value, &description, &hcl, &sensitive, &category)
+	return err
+}
+
+func (c *Connection) GetVarialbeSetByName(name string) (*tfe.VariableSet, error) {
+	options := &tfe.VariableSetListOptions{
+		ListOptions: tfe.ListOptions{PageSize: c.PageSize},
+	}
+	items, err := paginate(func(page int) ([]*tfe.VariableSet, int, error) {
+		options.ListOptions.PageNumber = page
+		vss, err := c.Client.VariableSets.List(c.Ctx, c.Org, options)
+		if err != nil {
+			return nil, 0, classifyError("variable sets", err)
+		}
+		return vss.Items, vss.NextPage, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range items {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return nil, &ErrNotFound{Resource: "variable set " + name, Err: tfe.ErrResourceNotFound}
+}
+
+func (c *Connection) AssignVariableSet(workspace string, variableSet string) error {
+	w, err := c.ReadWorkspace(workspace)
+	if err != nil {
+		return err
+	}
+	ctx := c.Ctx
+	options := &tfe.VariableSetApplyToWorkspacesOptions{
+		Workspaces: []*tfe.Workspace{w},
+	}
+	vs, err := c.GetVarialbeSetByName(variableSet)
+	if err != nil {
+		return err
+	}
+	if err := c.Client.VariableSets.ApplyToWorkspaces(ctx, vs.ID, options); err != nil {
+		return classifyError("variable set "+variableSet, err)
+	}
+	return nil
+}
+
+// WorkspaceHasVariableSet reports whether variableSet is already attached to
+// workspaceID, so callers can skip a redundant ApplyToWorkspaces call.
+func (c *Connection) WorkspaceHasVariableSet(workspaceID string, variableSet string) (bool, error) {
+	options := &tfe.VariableSetListOptions{
+		ListOptions: tfe.ListOptions{PageSize: c.PageSize},
+	}
+	items, err := paginate(func(page int) ([]*tfe.VariableSet, int, error) {
+		options.ListOptions.PageNumber = page
+		vss, err := c.Client.VariableSets.ListForWorkspace(c.Ctx, workspaceID, options)
+		if err != nil {
+			return nil, 0, classifyError("variable sets for workspace", err)
+		}
+		return vss.Items, vss.NextPage, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, vs := range items {
+		if vs.Name == variableSet {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (c *Connection) ReadVariableSet(variableSet string) ([]byte, error) {
+	vs, err := c.GetVarialbeSetByName(variableSet)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(vs)
+}
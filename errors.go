@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+// Exit codes returned by main for distinct error classes, so automation can
+// branch on failure mode instead of scraping log output.
+const (
+	ExitUsage                = 2
+	ExitNotFound             = 3
+	ExitUnauthorized         = 4
+	ExitAPI                  = 5
+	ExitTimeout              = 6
+	ExitAwaitingConfirmation = 7
+)
+
+// ErrNotFound indicates the requested TFE resource does not exist.
+type ErrNotFound struct {
+	Resource string
+	Err      error
+}
+
+func (e *ErrNotFound) Error() string { return fmt.Sprintf("%s not found: %v", e.Resource, e.Err) }
+func (e *ErrNotFound) Unwrap() error { return e.Err }
+
+// ErrUnauthorized indicates the TFE API rejected the request's credentials.
+type ErrUnauthorized struct{ Err error }
+
+func (e *ErrUnauthorized) Error() string { return fmt.Sprintf("unauthorized: %v", e.Err) }
+func (e *ErrUnauthorized) Unwrap() error { return e.Err }
+
+// ErrRateLimited indicates the TFE API throttled the request.
+type ErrRateLimited struct{ Err error }
+
+func (e *ErrRateLimited) Error() string { return fmt.Sprintf("rate limited: %v", e.Err) }
+func (e *ErrRateLimited) Unwrap() error { return e.Err }
+
+// ErrAwaitingConfirmation indicates a run stopped in a state where TFE is
+// waiting on an operator decision (e.g. planned, cost_estimated,
+// policy_checked) rather than one WaitForRun can keep polling toward.
+type ErrAwaitingConfirmation struct {
+	RunID  string
+	Status tfe.RunStatus
+}
+
+func (e *ErrAwaitingConfirmation) Error() string {
+	return fmt.Sprintf("run %s is awaiting confirmation (status: %s)", e.RunID, e.Status)
+}
+
+// classifyError wraps err from a go-tfe call into one of the typed errors
+// above when it recognizes the underlying API failure, so callers further up
+// the stack can branch on error class instead of parsing free-form messages.
+// resource is a short label such as "workspace foo" used in ErrNotFound.
+func classifyError(resource string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, tfe.ErrResourceNotFound) {
+		return &ErrNotFound{Resource: resource, Err: err}
+	}
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "unauthorized"), strings.Contains(msg, "invalid authentication"):
+		return &ErrUnauthorized{Err: err}
+	case strings.Contains(msg, "rate limit"), strings.Contains(msg, "too many requests"):
+		return &ErrRateLimited{Err: err}
+	case strings.Contains(msg, "not found"):
+		return &ErrNotFound{Resource: resource, Err: err}
+	}
+	return err
+}
+
+// ExitCode maps err to the process exit code main should use.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var notFound *ErrNotFound
+	var unauthorized *ErrUnauthorized
+	var rateLimited *ErrRateLimited
+	var awaitingConfirmation *ErrAwaitingConfirmation
+	switch {
+	case errors.As(err, &notFound):
+		return ExitNotFound
+	case errors.As(err, &unauthorized):
+		return ExitUnauthorized
+	case errors.As(err, &rateLimited):
+		return ExitAPI
+	case errors.As(err, &awaitingConfirmation):
+		return ExitAwaitingConfirmation
+	case errors.Is(err, context.DeadlineExceeded):
+		return ExitTimeout
+	default:
+		return ExitAPI
+	}
+}
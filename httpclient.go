@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryRoundTripper retries requests that hit TFE rate limiting or a server
+// error, honoring the API's own Retry-After/X-RateLimit-Reset hints with a
+// jittered exponential backoff as a fallback.
+type retryRoundTripper struct {
+	next       http.RoundTripper
+	maxRetries int
+	maxWait    time.Duration
+}
+
+// newRetryRoundTripper wraps next (or http.DefaultTransport if nil) with
+// rate-limit-aware retry/backoff.
+func newRetryRoundTripper(next http.RoundTripper, maxRetries int, maxWait time.Duration) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &retryRoundTripper{next: next, maxRetries: maxRetries, maxWait: maxWait}
+}
+
+func (rt *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		resp, err := rt.next.RoundTrip(req)
+		if err != nil || !shouldRetry(resp) || attempt >= rt.maxRetries {
+			return resp, err
+		}
+		if req.Body != nil && req.GetBody == nil {
+			// Body was consumed by this attempt and can't be replayed; give
+			// up retrying rather than resend it empty.
+			return resp, nil
+		}
+		wait := retryWait(resp, attempt, rt.maxWait)
+		resp.Body.Close()
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func shouldRetry(resp *http.Response) bool {
+	return resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500)
+}
+
+// retryWait computes how long to wait before the next attempt, preferring the
+// server's own Retry-After/X-RateLimit-Reset headers and otherwise falling
+// back to jittered exponential backoff. The result is capped at maxWait.
+func retryWait(resp *http.Response, attempt int, maxWait time.Duration) time.Duration {
+	if resp != nil {
+		if v := resp.Header.Get("Retry-After"); v != "" {
+			if secs, err := strconv.Atoi(v); err == nil {
+				return capDuration(time.Duration(secs)*time.Second, maxWait)
+			}
+		}
+		if v := resp.Header.Get("X-RateLimit-Reset"); v != "" {
+			if secs, err := strconv.ParseFloat(v, 64); err == nil {
+				return capDuration(time.Duration(secs*float64(time.Second)), maxWait)
+			}
+		}
+	}
+	base := time.Duration(math.Pow(2, float64(attempt))) * time.Second
+	jitter := time.Duration(rand.Int63n(int64(time.Second)))
+	return capDuration(base+jitter, maxWait)
+}
+
+func capDuration(d, max time.Duration) time.Duration {
+	if max > 0 && d > max {
+		return max
+	}
+	return d
+}
@@ -0,0 +1,20 @@
+package main
+
+// paginate drains every page of a go-tfe list endpoint into a single slice.
+// fetch is called with increasing page numbers, starting at 1, until it
+// reports nextPage == 0.
+func paginate[T any](fetch func(page int) (items []T, nextPage int, err error)) ([]T, error) {
+	var all []T
+	page := 1
+	for {
+		items, nextPage, err := fetch(page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if nextPage == 0 {
+			return all, nil
+		}
+		page = nextPage
+	}
+}
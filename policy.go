@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+// GetCostEstimate returns the cost estimate attached to runID's run, if any.
+func (c *Connection) GetCostEstimate(runID string) (*tfe.CostEstimate, error) {
+	ctx := c.Ctx
+	r, err := c.Client.Runs.ReadWithOptions(ctx, runID, &tfe.RunReadOptions{
+		Include: []tfe.RunIncludeOpt{tfe.RunCostEstimate},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if r.CostEstimate == nil {
+		return nil, nil
+	}
+	return c.Client.CostEstimates.Read(ctx, r.CostEstimate.ID)
+}
+
+// ListPolicyChecks returns every policy check attached to runID's run.
+func (c *Connection) ListPolicyChecks(runID string) ([]*tfe.PolicyCheck, error) {
+	ctx := c.Ctx
+	options := &tfe.PolicyCheckListOptions{
+		ListOptions: tfe.ListOptions{PageNumber: 1},
+	}
+	var checks []*tfe.PolicyCheck
+	for {
+		pcs, err := c.Client.PolicyChecks.List(ctx, runID, options)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, pcs.Items...)
+		options.ListOptions.PageNumber = pcs.NextPage
+		if pcs.NextPage == 0 {
+			break
+		}
+	}
+	return checks, nil
+}
+
+// OverridePolicyCheck overrides a soft-mandatory policy check that failed.
+// Note go-tfe's Override endpoint takes no comment field, so msg is not
+// recorded anywhere; it exists only for parity with the other Run* operator
+// actions (ApplyRun, DiscardRun, CancelRun) that do take one.
+func (c *Connection) OverridePolicyCheck(policyCheckID string, msg string) error {
+	ctx := c.Ctx
+	_, err := c.Client.PolicyChecks.Override(ctx, policyCheckID)
+	return err
+}
+
+// RunStatus is the combined plan/cost/policy/task-result snapshot returned by
+// `run status`.
+type RunStatus struct {
+	RunID        string             `json:"run_id"`
+	Status       tfe.RunStatus      `json:"status"`
+	Plan         *tfe.Plan          `json:"plan,omitempty"`
+	CostEstimate *tfe.CostEstimate  `json:"cost_estimate,omitempty"`
+	PolicyChecks []*tfe.PolicyCheck `json:"policy_checks,omitempty"`
+	TaskStages   []*tfe.TaskStage   `json:"task_stages,omitempty"`
+}
+
+// GetRunStatus assembles a RunStatus for runID by combining the run, its cost
+// estimate, its policy checks, and any run-task stages.
+func (c *Connection) GetRunStatus(runID string) (*RunStatus, error) {
+	ctx := c.Ctx
+	r, err := c.Client.Runs.ReadWithOptions(ctx, runID, &tfe.RunReadOptions{
+		Include: []tfe.RunIncludeOpt{tfe.RunPlan, tfe.RunCostEstimate, tfe.RunTaskStages},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status := &RunStatus{RunID: runID, Status: r.Status, Plan: r.Plan}
+
+	if r.CostEstimate != nil {
+		estimate, err := c.Client.CostEstimates.Read(ctx, r.CostEstimate.ID)
+		if err != nil {
+			return nil, err
+		}
+		status.CostEstimate = estimate
+	}
+
+	checks, err := c.ListPolicyChecks(runID)
+	if err != nil {
+		return nil, err
+	}
+	status.PolicyChecks = checks
+	status.TaskStages = r.TaskStages
+
+	return status, nil
+}
+
+// hardFailedPolicyCheck returns the first policy check in checks that is a
+// hard failure, or nil if there is none.
+func hardFailedPolicyCheck(checks []*tfe.PolicyCheck) *tfe.PolicyCheck {
+	for _, check := range checks {
+		switch check.Status {
+		case tfe.PolicyHardFailed:
+			return check
+		}
+	}
+	return nil
+}
+
+// ConfirmOptions gates ApplyRun behind cost and policy checks.
+type ConfirmOptions struct {
+	RequireCostUnder  float64
+	RequirePolicyPass bool
+}
+
+// ConfirmRun validates opts against the run's current cost estimate and policy
+// checks and, if they pass, applies the run.
+func (c *Connection) ConfirmRun(runID string, message string, opts ConfirmOptions) error {
+	status, err := c.GetRunStatus(runID)
+	if err != nil {
+		return err
+	}
+
+	if opts.RequireCostUnder > 0 {
+		if status.CostEstimate == nil {
+			return fmt.Errorf("run %s: --require-cost-under set but no cost estimate is available for this run", runID)
+		}
+		delta := status.CostEstimate.DeltaMonthlyCost
+		var cost float64
+		if _, err := fmt.Sscanf(delta, "%f", &cost); err != nil {
+			return fmt.Errorf("run %s: --require-cost-under set but cost delta %q could not be parsed: %w", runID, delta, err)
+		}
+		if cost >= opts.RequireCostUnder {
+			return fmt.Errorf("run %s: projected monthly cost delta %s is at or above threshold %.2f", runID, delta, opts.RequireCostUnder)
+		}
+	}
+
+	if opts.RequirePolicyPass {
+		if check := hardFailedPolicyCheck(status.PolicyChecks); check != nil {
+			return fmt.Errorf("run %s: policy check %s failed and is mandatory", runID, check.ID)
+		}
+	}
+
+	return c.ApplyRun(runID, message)
+}
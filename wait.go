@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+// terminalRunStatuses are the run states WaitForRun stops polling on because
+// the run itself is done and won't progress further on its own.
+var terminalRunStatuses = map[tfe.RunStatus]bool{
+	tfe.RunApplied:            true,
+	tfe.RunPlannedAndFinished: true,
+	tfe.RunErrored:            true,
+	tfe.RunCanceled:           true,
+	tfe.RunDiscarded:          true,
+	tfe.RunPolicySoftFailed:   true,
+}
+
+// awaitingConfirmationStatuses are run states where TFE has stopped and is
+// waiting on an operator decision (apply/discard, or an override) rather than
+// progressing toward apply on its own, e.g. a non-auto-apply workspace.
+// WaitForRun also stops polling here, since waiting for --timeout to elapse
+// on a run that is simply waiting for a human would defeat the point of
+// `run wait` in CI; it reports the distinction via ErrAwaitingConfirmation.
+var awaitingConfirmationStatuses = map[tfe.RunStatus]bool{
+	tfe.RunPlanned:        true,
+	tfe.RunCostEstimated:  true,
+	tfe.RunPolicyChecked:  true,
+	tfe.RunPolicyOverride: true,
+}
+
+// WaitOptions configures WaitForRun's polling behaviour.
+type WaitOptions struct {
+	PollInterval time.Duration
+	Timeout      time.Duration
+}
+
+// runStatusEvent is one JSON line emitted to stdout as a run transitions status.
+type runStatusEvent struct {
+	RunID  string        `json:"run_id"`
+	Status tfe.RunStatus `json:"status"`
+}
+
+// WaitForRun blocks until runID reaches a terminal status, a context cancellation
+// (e.g. SIGINT) arrives, or the timeout elapses. On cancellation it calls CancelRun
+// before returning ctx.Err(). It also returns, with ErrAwaitingConfirmation, when
+// the run stops in one of awaitingConfirmationStatuses. Status transitions are
+// written as JSON lines to w.
+func (c *Connection) WaitForRun(ctx context.Context, runID string, opts WaitOptions, w io.Writer) (*tfe.Run, error) {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	var lastStatus tfe.RunStatus
+	encoder := json.NewEncoder(w)
+	for {
+		r, err := c.Client.Runs.Read(ctx, runID)
+		if err != nil {
+			if ctx.Err() != nil {
+				_ = c.CancelRun(runID, "canceled: "+ctx.Err().Error())
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		if r.Status != lastStatus {
+			lastStatus = r.Status
+			if err := encoder.Encode(runStatusEvent{RunID: runID, Status: r.Status}); err != nil {
+				return nil, err
+			}
+		}
+		if terminalRunStatuses[r.Status] {
+			return r, nil
+		}
+		if awaitingConfirmationStatuses[r.Status] {
+			return r, &ErrAwaitingConfirmation{RunID: runID, Status: r.Status}
+		}
+
+		select {
+		case <-ctx.Done():
+			_ = c.CancelRun(runID, "canceled: "+ctx.Err().Error())
+			return r, ctx.Err()
+		case <-time.After(opts.PollInterval):
+		}
+	}
+}
+
+// StreamLogs copies plan/apply logs for runID to w as they are produced, returning
+// once the underlying log stream hits EOF and the run has left the corresponding
+// phase (queued/running for that operation). Each call to Plans.Logs/Applies.Logs
+// returns the log from the start, so written tracks how much has already been
+// copied to w and is skipped on the next iteration instead of being re-copied.
+func (c *Connection) StreamLogs(ctx context.Context, runID string, op LogOperation, w io.Writer) error {
+	var written int64
+	for {
+		r, err := c.Client.Runs.Read(ctx, runID)
+		if err != nil {
+			return err
+		}
+
+		var logs io.Reader
+		switch op {
+		case PlanOperation:
+			logs, err = c.Client.Plans.Logs(ctx, r.Plan.ID)
+		case ApplyOperation:
+			logs, err = c.Client.Applies.Logs(ctx, r.Apply.ID)
+		default:
+			return fmt.Errorf("unknown log operation %q", op)
+		}
+		if err != nil {
+			return err
+		}
+
+		if written > 0 {
+			if _, err := io.CopyN(io.Discard, logs, written); err != nil && err != io.EOF {
+				return err
+			}
+		}
+
+		n, err := io.Copy(w, logs)
+		if err != nil {
+			return err
+		}
+		written += n
+
+		if c.phaseFinished(r, op) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// contextWithInterrupt returns a context that is canceled when the process
+// receives SIGINT, along with a func to stop listening once it's no longer needed.
+func contextWithInterrupt(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(ctx, os.Interrupt)
+}
+
+func (c *Connection) phaseFinished(r *tfe.Run, op LogOperation) bool {
+	switch op {
+	case PlanOperation:
+		switch r.Status {
+		case tfe.RunPending, tfe.RunPlanQueued, tfe.RunPlanning:
+			return false
+		}
+		return true
+	case ApplyOperation:
+		switch r.Status {
+		case tfe.RunApplyQueued, tfe.RunApplying:
+			return false
+		}
+		return true
+	}
+	return true
+}
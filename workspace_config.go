@@ -0,0 +1,429 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+// WorkspacesConfig is the declarative spec read by `workspace apply-config`.
+type WorkspacesConfig struct {
+	Workspaces []WorkspaceConfig `yaml:"workspaces"`
+}
+
+// WorkspaceConfig describes the desired state of a single workspace.
+type WorkspaceConfig struct {
+	Name             string          `yaml:"name"`
+	WorkingDir       string          `yaml:"working_dir"`
+	VCS              *VCSConfig      `yaml:"vcs,omitempty"`
+	TerraformVersion string          `yaml:"terraform_version,omitempty"`
+	ExecutionMode    string          `yaml:"execution_mode,omitempty"`
+	AutoApply        bool            `yaml:"auto_apply"`
+	Tags             []string        `yaml:"tags,omitempty"`
+	Variables        VariablesConfig `yaml:"variables,omitempty"`
+	VariableSets     []string        `yaml:"variable_sets,omitempty"`
+}
+
+// VCSConfig is the VCS repository a workspace should be linked to.
+type VCSConfig struct {
+	Repo        string `yaml:"repo"`
+	Branch      string `yaml:"branch"`
+	OAuthClient string `yaml:"oauth_client"`
+}
+
+// VariablesConfig splits the desired variables by category.
+type VariablesConfig struct {
+	Terraform []VariableConfig `yaml:"terraform,omitempty"`
+	Env       []VariableConfig `yaml:"env,omitempty"`
+}
+
+// VariableConfig is a single desired Terraform or environment variable.
+type VariableConfig struct {
+	Name        string `yaml:"name"`
+	Value       string `yaml:"value"`
+	Description string `yaml:"description,omitempty"`
+	Sensitive   bool   `yaml:"sensitive,omitempty"`
+	HCL         bool   `yaml:"hcl,omitempty"`
+}
+
+// ResourceDiff is one reconciled resource reported back to the operator.
+type ResourceDiff struct {
+	Workspace string `json:"workspace"`
+	Resource  string `json:"resource"`
+	Action    string `json:"action"` // "create", "update", "delete", "noop"
+	Detail    string `json:"detail,omitempty"`
+}
+
+// ReconcileOptions controls how ApplyConfig reconciles the tree.
+type ReconcileOptions struct {
+	DryRun bool
+	Prune  bool
+}
+
+// ApplyConfig reconciles Terraform Cloud/Enterprise against cfg: creating
+// missing workspaces, updating drifted attributes and variables, and
+// optionally pruning variables and workspaces absent from cfg. It returns the
+// full list of resource diffs whether or not DryRun is set.
+func (c *Connection) ApplyConfig(cfg WorkspacesConfig, opts ReconcileOptions) ([]ResourceDiff, error) {
+	var diffs []ResourceDiff
+
+	desired := make(map[string]bool, len(cfg.Workspaces))
+	for _, wc := range cfg.Workspaces {
+		desired[wc.Name] = true
+		wsDiffs, err := c.reconcileWorkspace(wc, opts)
+		if err != nil {
+			return diffs, fmt.Errorf("workspace %s: %w", wc.Name, err)
+		}
+		diffs = append(diffs, wsDiffs...)
+	}
+
+	if opts.Prune {
+		pruned, err := c.pruneWorkspaces(desired, opts)
+		if err != nil {
+			return diffs, err
+		}
+		diffs = append(diffs, pruned...)
+	}
+
+	return diffs, nil
+}
+
+func (c *Connection) reconcileWorkspace(wc WorkspaceConfig, opts ReconcileOptions) ([]ResourceDiff, error) {
+	var diffs []ResourceDiff
+
+	w, err := c.ReadWorkspace(wc.Name)
+	if err != nil {
+		diffs = append(diffs, ResourceDiff{Workspace: wc.Name, Resource: "workspace", Action: "create"})
+		if opts.DryRun {
+			// w doesn't exist yet, so there's nothing to list variables
+			// against; report what creating it would also create.
+			return append(diffs, dryRunVariableDiffs(wc)...), nil
+		}
+		w, err = c.createWorkspaceFromConfig(wc)
+		if err != nil {
+			return diffs, err
+		}
+	} else {
+		d, err := c.workspaceDrift(w, wc)
+		if err != nil {
+			return diffs, err
+		}
+		if d != "" {
+			diffs = append(diffs, ResourceDiff{Workspace: wc.Name, Resource: "workspace", Action: "update", Detail: d})
+			if !opts.DryRun {
+				if err := c.updateWorkspaceFromConfig(w, wc); err != nil {
+					return diffs, err
+				}
+			}
+		} else {
+			diffs = append(diffs, ResourceDiff{Workspace: wc.Name, Resource: "workspace", Action: "noop"})
+		}
+	}
+
+	varDiffs, err := c.reconcileVariables(w.ID, wc, opts)
+	if err != nil {
+		return diffs, err
+	}
+	diffs = append(diffs, varDiffs...)
+
+	for _, vsName := range wc.VariableSets {
+		attached, err := c.WorkspaceHasVariableSet(w.ID, vsName)
+		if err != nil {
+			return diffs, err
+		}
+		if attached {
+			diffs = append(diffs, ResourceDiff{Workspace: wc.Name, Resource: "variable_set:" + vsName, Action: "noop"})
+			continue
+		}
+		diffs = append(diffs, ResourceDiff{Workspace: wc.Name, Resource: "variable_set:" + vsName, Action: "update"})
+		if !opts.DryRun {
+			if err := c.AssignVariableSet(wc.Name, vsName); err != nil {
+				return diffs, err
+			}
+		}
+	}
+
+	return diffs, nil
+}
+
+// dryRunVariableDiffs reports the variable and variable_set diffs that
+// reconcileVariables/reconcileWorkspace would produce for wc, for use when the
+// workspace itself doesn't exist yet and so can't actually be queried.
+func dryRunVariableDiffs(wc WorkspaceConfig) []ResourceDiff {
+	var diffs []ResourceDiff
+	for _, vc := range wc.Variables.Terraform {
+		diffs = append(diffs, ResourceDiff{Workspace: wc.Name, Resource: "variable:" + vc.Name, Action: "create"})
+	}
+	for _, vc := range wc.Variables.Env {
+		diffs = append(diffs, ResourceDiff{Workspace: wc.Name, Resource: "variable:" + vc.Name, Action: "create"})
+	}
+	for _, vsName := range wc.VariableSets {
+		diffs = append(diffs, ResourceDiff{Workspace: wc.Name, Resource: "variable_set:" + vsName, Action: "update"})
+	}
+	return diffs
+}
+
+// tagsFromNames builds the []*tfe.Tag WorkspaceCreateOptions.Tags expects out
+// of the plain tag names used in WorkspaceConfig.
+func tagsFromNames(names []string) []*tfe.Tag {
+	if len(names) == 0 {
+		return nil
+	}
+	tags := make([]*tfe.Tag, len(names))
+	for i, n := range names {
+		tags[i] = &tfe.Tag{Name: n}
+	}
+	return tags
+}
+
+func (c *Connection) createWorkspaceFromConfig(wc WorkspaceConfig) (*tfe.Workspace, error) {
+	ctx := c.Ctx
+	options := tfe.WorkspaceCreateOptions{
+		Name:             tfe.String(wc.Name),
+		WorkingDirectory: &wc.WorkingDir,
+		AutoApply:        tfe.Bool(wc.AutoApply),
+		Tags:             tagsFromNames(wc.Tags),
+	}
+	if wc.TerraformVersion != "" {
+		options.TerraformVersion = &wc.TerraformVersion
+	}
+	if wc.ExecutionMode != "" {
+		options.ExecutionMode = &wc.ExecutionMode
+	}
+	if wc.VCS != nil {
+		vcsRepo, err := c.GetVCSProviderFromOAuthClient(wc.VCS.OAuthClient, wc.VCS.Branch, wc.VCS.Repo)
+		if err != nil {
+			return nil, err
+		}
+		options.VCSRepo = vcsRepo
+	}
+	return c.Client.Workspaces.Create(ctx, c.Org, options)
+}
+
+func (c *Connection) updateWorkspaceFromConfig(w *tfe.Workspace, wc WorkspaceConfig) error {
+	options := &tfe.WorkspaceUpdateOptions{
+		WorkingDirectory: &wc.WorkingDir,
+		AutoApply:        tfe.Bool(wc.AutoApply),
+	}
+	if wc.TerraformVersion != "" {
+		options.TerraformVersion = &wc.TerraformVersion
+	}
+	if wc.ExecutionMode != "" {
+		options.ExecutionMode = &wc.ExecutionMode
+	}
+	if wc.VCS != nil {
+		vcsRepo, err := c.GetVCSProviderFromOAuthClient(wc.VCS.OAuthClient, wc.VCS.Branch, wc.VCS.Repo)
+		if err != nil {
+			return err
+		}
+		options.VCSRepo = vcsRepo
+	}
+	if err := c.UpdateWorkspace(w.Name, options); err != nil {
+		return err
+	}
+	if wc.VCS == nil && w.VCSRepo != nil {
+		if _, err := c.Client.Workspaces.RemoveVCSConnection(c.Ctx, w.ID); err != nil {
+			return classifyError("workspace "+w.Name, err)
+		}
+	}
+	// Tags aren't part of WorkspaceUpdateOptions; they're managed through
+	// their own add/remove endpoints.
+	return c.syncWorkspaceTags(w, wc.Tags)
+}
+
+// syncWorkspaceTags adds/removes tags on w so its tag set matches desired.
+func (c *Connection) syncWorkspaceTags(w *tfe.Workspace, desired []string) error {
+	ctx := c.Ctx
+	current := make(map[string]bool, len(w.TagNames))
+	for _, t := range w.TagNames {
+		current[t] = true
+	}
+	want := make(map[string]bool, len(desired))
+	for _, t := range desired {
+		want[t] = true
+	}
+
+	var toAdd, toRemove []*tfe.Tag
+	for _, t := range desired {
+		if !current[t] {
+			toAdd = append(toAdd, &tfe.Tag{Name: t})
+		}
+	}
+	for _, t := range w.TagNames {
+		if !want[t] {
+			toRemove = append(toRemove, &tfe.Tag{Name: t})
+		}
+	}
+
+	if len(toAdd) > 0 {
+		if err := c.Client.Workspaces.AddTags(ctx, w.ID, tfe.WorkspaceAddTagsOptions{Tags: toAdd}); err != nil {
+			return err
+		}
+	}
+	if len(toRemove) > 0 {
+		if err := c.Client.Workspaces.RemoveTags(ctx, w.ID, tfe.WorkspaceRemoveTagsOptions{Tags: toRemove}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameTagSet reports whether a and b contain the same tag names, ignoring order.
+func sameTagSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	ac := append([]string(nil), a...)
+	bc := append([]string(nil), b...)
+	sort.Strings(ac)
+	sort.Strings(bc)
+	for i := range ac {
+		if ac[i] != bc[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// workspaceDrift returns a human-readable summary of attributes that differ
+// between the live workspace and the desired config, or "" if none do. It
+// calls out to the TFE API to resolve wc.VCS's oauth client, so it can report
+// on VCS drift too.
+func (c *Connection) workspaceDrift(w *tfe.Workspace, wc WorkspaceConfig) (string, error) {
+	drift := ""
+	if w.WorkingDirectory != wc.WorkingDir {
+		drift += fmt.Sprintf("working_dir: %q -> %q; ", w.WorkingDirectory, wc.WorkingDir)
+	}
+	if w.AutoApply != wc.AutoApply {
+		drift += fmt.Sprintf("auto_apply: %v -> %v; ", w.AutoApply, wc.AutoApply)
+	}
+	if wc.TerraformVersion != "" && w.TerraformVersion != wc.TerraformVersion {
+		drift += fmt.Sprintf("terraform_version: %q -> %q; ", w.TerraformVersion, wc.TerraformVersion)
+	}
+	if wc.ExecutionMode != "" && w.ExecutionMode != wc.ExecutionMode {
+		drift += fmt.Sprintf("execution_mode: %q -> %q; ", w.ExecutionMode, wc.ExecutionMode)
+	}
+	if !sameTagSet(w.TagNames, wc.Tags) {
+		drift += fmt.Sprintf("tags: %v -> %v; ", w.TagNames, wc.Tags)
+	}
+	if wc.VCS != nil {
+		vcsRepo, err := c.GetVCSProviderFromOAuthClient(wc.VCS.OAuthClient, wc.VCS.Branch, wc.VCS.Repo)
+		if err != nil {
+			return "", err
+		}
+		if w.VCSRepo == nil || w.VCSRepo.Identifier != *vcsRepo.Identifier || w.VCSRepo.Branch != *vcsRepo.Branch || w.VCSRepo.OAuthTokenID != *vcsRepo.OAuthTokenID {
+			drift += fmt.Sprintf("vcs: -> %s@%s; ", wc.VCS.Repo, wc.VCS.Branch)
+		}
+	} else if w.VCSRepo != nil {
+		drift += "vcs: unlinking; "
+	}
+	return drift, nil
+}
+
+func (c *Connection) reconcileVariables(workspaceID string, wc WorkspaceConfig, opts ReconcileOptions) ([]ResourceDiff, error) {
+	ctx := c.Ctx
+	items, err := paginate(func(page int) ([]*tfe.Variable, int, error) {
+		vl, err := c.Client.Variables.List(ctx, workspaceID, &tfe.VariableListOptions{
+			ListOptions: tfe.ListOptions{PageNumber: page, PageSize: c.PageSize},
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		return vl.Items, vl.NextPage, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]*tfe.Variable, len(items))
+	for _, v := range items {
+		byKey[string(v.Category)+"/"+v.Key] = v
+	}
+
+	var diffs []ResourceDiff
+	wanted := make(map[string]bool)
+
+	reconcileOne := func(vc VariableConfig, category tfe.CategoryType) error {
+		k := string(category) + "/" + vc.Name
+		wanted[k] = true
+		current, found := byKey[k]
+		if !found {
+			diffs = append(diffs, ResourceDiff{Workspace: wc.Name, Resource: "variable:" + vc.Name, Action: "create"})
+			if opts.DryRun {
+				return nil
+			}
+			hcl, sensitive := vc.HCL, vc.Sensitive
+			_, err := c.addTerraformVariable(&vc.Name, &wc.Name, &vc.Value, &vc.Description, &hcl, &sensitive, &category)
+			return err
+		}
+		// The API never returns a sensitive variable's value, so comparing it
+		// against the desired value would always look changed; skip that
+		// comparison for sensitive variables instead of forcing an update.
+		valueChanged := !current.Sensitive && current.Value != vc.Value
+		if valueChanged || current.Description != vc.Description || current.HCL != vc.HCL {
+			diffs = append(diffs, ResourceDiff{Workspace: wc.Name, Resource: "variable:" + vc.Name, Action: "update"})
+			if opts.DryRun {
+				return nil
+			}
+			_, err := c.Client.Variables.Update(ctx, workspaceID, current.ID, tfe.VariableUpdateOptions{
+				Key:         &vc.Name,
+				Value:       &vc.Value,
+				Description: &vc.Description,
+				HCL:         &vc.HCL,
+				Sensitive:   &vc.Sensitive,
+			})
+			return err
+		}
+		diffs = append(diffs, ResourceDiff{Workspace: wc.Name, Resource: "variable:" + vc.Name, Action: "noop"})
+		return nil
+	}
+
+	for _, vc := range wc.Variables.Terraform {
+		if err := reconcileOne(vc, tfe.CategoryTerraform); err != nil {
+			return diffs, err
+		}
+	}
+	for _, vc := range wc.Variables.Env {
+		if err := reconcileOne(vc, tfe.CategoryEnv); err != nil {
+			return diffs, err
+		}
+	}
+
+	if opts.Prune {
+		for k, v := range byKey {
+			if wanted[k] {
+				continue
+			}
+			diffs = append(diffs, ResourceDiff{Workspace: wc.Name, Resource: "variable:" + v.Key, Action: "delete"})
+			if !opts.DryRun {
+				if err := c.Client.Variables.Delete(ctx, workspaceID, v.ID); err != nil {
+					return diffs, err
+				}
+			}
+		}
+	}
+
+	return diffs, nil
+}
+
+func (c *Connection) pruneWorkspaces(desired map[string]bool, opts ReconcileOptions) ([]ResourceDiff, error) {
+	ctx := c.Ctx
+	var diffs []ResourceDiff
+	names, err := c.ListWorkspaces()
+	if err != nil {
+		return diffs, err
+	}
+	for _, name := range names {
+		if desired[name] {
+			continue
+		}
+		diffs = append(diffs, ResourceDiff{Workspace: name, Resource: "workspace", Action: "delete"})
+		if !opts.DryRun {
+			if err := c.Client.Workspaces.Delete(ctx, c.Org, name); err != nil {
+				return diffs, err
+			}
+		}
+	}
+	return diffs, nil
+}